@@ -4,58 +4,182 @@
 
 package math
 
-import "math"
+// Pow returns x**y, the base-x exponential of y.
+//
+// Special cases are (in order):
+//	Pow(x, ±0) = 1 for any x
+//	Pow(1, y) = 1 for any y
+//	Pow(x, 1) = x for any x
+//	Pow(NaN, y) = NaN except Pow(NaN, 0) = 1
+//	Pow(x, NaN) = NaN except Pow(1, NaN) = 1
+//	Pow(±0, y) = ±Inf for y an odd integer < 0
+//	Pow(±0, -Inf) = +Inf
+//	Pow(±0, +Inf) = +0
+//	Pow(±0, y) = +Inf for finite y < 0 and not an odd integer
+//	Pow(±0, y) = ±0 for y an odd integer > 0
+//	Pow(±0, y) = +0 for finite y > 0 and not an odd integer
+//	Pow(-1, ±Inf) = 1
+//	Pow(x, +Inf) = +Inf for |x| > 1
+//	Pow(x, -Inf) = +0 for |x| > 1
+//	Pow(x, +Inf) = +0 for |x| < 1
+//	Pow(x, -Inf) = +Inf for |x| < 1
+//	Pow(+Inf, y) = +Inf for y > 0
+//	Pow(+Inf, y) = +0 for y < 0
+//	Pow(-Inf, y) = Pow(-0, -y)
+//	Pow(x, y) = NaN for finite x < 0 and finite non-integer y
+func Pow(x, y float64) float64 {
+	switch {
+	case y == 0 || x == 1:
+		return 1
+	case y == 1:
+		return x
+	case IsNaN(x) || IsNaN(y):
+		return NaN()
+	case x == 0:
+		switch {
+		case y < 0:
+			if isOddInt(y) {
+				return Copysign(Inf(1), x)
+			}
+			return Inf(1)
+		default: // y > 0
+			if isOddInt(y) {
+				return x
+			}
+			return 0
+		}
+	case IsInf(y, 0):
+		switch {
+		case x == -1:
+			return 1
+		case (Abs(x) < 1) == IsInf(y, 1):
+			return 0
+		default:
+			return Inf(1)
+		}
+	case IsInf(x, 0):
+		if IsInf(x, -1) {
+			// -Inf**y = Pow(-0, -y); 1/x is -0 here since x is -Inf.
+			return Pow(1/x, -y)
+		}
+		if y < 0 {
+			return 0
+		}
+		return Inf(1)
+	}
 
-/*
-	arg1 ^ arg2 (exponentiation)
- */
+	// Beyond this point x is finite and nonzero, y is finite and
+	// nonzero, and neither is 1 (either may still be -1).
+	yi, yf := Modf(Abs(y))
+	if yf != 0 && x < 0 {
+		return NaN()
+	}
 
-export func Pow(arg1,arg2 float64) float64 {
-	if arg2 < 0 {
-		return 1/Pow(arg1, -arg2);
+	neg := false
+	if x < 0 {
+		// yi is an integer (checked above); preserve the sign of
+		// x**y by parity without recursing back through Pow.
+		neg = int64(yi)&1 == 1
+		x = -x
 	}
-	if arg1 <= 0 {
-		if(arg1 == 0) {
-			if arg2 <= 0 {
-				return sys.NaN();
-			}
-			return 0;
-		}
 
-		temp := Floor(arg2);
-		if temp != arg2 {
-			panic(sys.NaN());
-		}
+	// x**y = x**yi * x**yf, computed separately so the fractional
+	// part can go through Exp2/Log2 and the integer part through
+	// plain binary exponentiation; this avoids Pow ever recursing
+	// on the exponent the way a naive implementation would.
+	frac := 1.0
+	if yf != 0 {
+		frac = exp2Frac(yf, x)
+	}
 
-		l := int32(temp);
-		if l&1 != 0 {
-			return -Pow(-arg1, arg2);
-		}
-		return Pow(-arg1, arg2);
+	// yi can be far too large to fit in a uint64 (e.g. y ~ 1e300);
+	// converting it directly would be an out-of-range float->int
+	// conversion, which Go leaves implementation-defined. x here is
+	// already positive (the sign was split off above), so any yi
+	// that large drives x**yi to 0, 1, or +Inf depending only on
+	// whether x is below, at, or above 1 -- compute that directly
+	// rather than risking the conversion.
+	const maxSafeExponent = 1 << 63
+	var whole float64
+	switch {
+	case yi < maxSafeExponent:
+		whole = ipow(x, uint64(yi))
+	case x == 1:
+		whole = 1
+	case x < 1:
+		whole = 0
+	default:
+		whole = Inf(1)
 	}
 
-	temp := Floor(arg2);
-	if temp != arg2 {
-		if arg2-temp == .5 {
-			if temp == 0 {
-				return Sqrt(arg1);
-			}
-			return Pow(arg1, temp) * Sqrt(arg1);
-		}
-		return Exp(arg2 * Log(arg1));
+	ans := whole * frac
+	if neg {
+		ans = -ans
+	}
+	if y < 0 {
+		ans = 1 / ans
 	}
+	return ans
+}
 
-	l := int32(temp);
-	temp = 1;
-	for {
-		if l&1 != 0 {
-			temp = temp*arg1;
+// ipow returns x**n for a non-negative integer n, computed by
+// right-to-left binary exponentiation: O(log n) multiplications,
+// no allocation, no recursion.
+func ipow(x float64, n uint64) float64 {
+	ans := 1.0
+	for n != 0 {
+		if n&1 == 1 {
+			ans *= x
 		}
-		l >>= 1;
-		if l == 0 {
-			return temp;
+		n >>= 1
+		if n != 0 {
+			x *= x
 		}
-		arg1 *= arg1;
 	}
-	panic("unreachable")
+	return ans
+}
+
+// exp2Frac returns x**f for 0 < f < 1 and x > 0, as Exp2(f*Log2(x)).
+// Log2(x) is computed to extra precision as a hi+lo pair (Log(x)*Log2E
+// formed via Dekker's two-product so that the rounding error of the
+// multiplication itself is recovered as lo) so that the f*Log2(x)
+// multiplication doesn't lose the low bits of Log2(x) to rounding
+// before Exp2 ever sees them.
+func exp2Frac(f, x float64) float64 {
+	hi, lo := log2(x)
+	return Exp2(f*hi + f*lo)
+}
+
+// dekkerSplit is 2**27+1; multiplying a float64 by it and
+// subtracting back out the high part is the standard trick for
+// splitting a double into a pair of halves whose product with
+// another double can be formed without rounding error.
+const dekkerSplit = 134217729.0 // 2**27 + 1
+
+// log2 returns Log2(x) as a hi+lo pair: hi is the rounded value of
+// Log(x)*Log2E and lo is the exact rounding error of that
+// multiplication, recovered via Dekker's two-product (split both
+// operands with dekkerSplit, then reassemble the product from the
+// split halves and subtract off the rounded result).
+func log2(x float64) (hi, lo float64) {
+	a := Log(x)
+	b := Log2E
+
+	ca := dekkerSplit * a
+	aHi := ca - (ca - a)
+	aLo := a - aHi
+
+	cb := dekkerSplit * b
+	bHi := cb - (cb - b)
+	bLo := b - bHi
+
+	hi = a * b
+	lo = ((aHi*bHi - hi) + aHi*bLo + aLo*bHi) + aLo*bLo
+	return hi, lo
+}
+
+// isOddInt reports whether x is an odd integer. It reports false for ±Inf.
+func isOddInt(x float64) bool {
+	xi, xf := Modf(x)
+	return xf == 0 && int64(xi)&1 == 1
 }
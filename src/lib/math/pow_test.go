@@ -0,0 +1,175 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package math
+
+import (
+	refmath "math"
+	"testing"
+)
+
+var powSpecialCases = []struct {
+	x, y, want float64
+}{
+	// Pow(x, ±0) = 1 for any x
+	{2, 0, 1},
+	{Inf(1), 0, 1},
+	{NaN(), 0, 1},
+
+	// Pow(1, y) = 1 for any y
+	{1, 2, 1},
+	{1, Inf(1), 1},
+	{1, NaN(), 1},
+
+	// Pow(x, 1) = x
+	{7.5, 1, 7.5},
+
+	// Pow(NaN, y) = NaN except Pow(NaN, 0) = 1 (checked above)
+	{NaN(), 2, NaN()},
+
+	// Pow(±0, y)
+	{0, -3, Inf(1)},       // odd negative integer exponent
+	{Copysign(0, -1), -3, Inf(-1)},
+	{0, -4, Inf(1)},       // even negative integer exponent
+	{0, 3, 0},             // odd positive integer exponent
+	{Copysign(0, -1), 3, Copysign(0, -1)},
+	{0, 4, 0},
+
+	// Pow(-1, ±Inf) = 1
+	{-1, Inf(1), 1},
+	{-1, Inf(-1), 1},
+
+	// Pow(x, ±Inf) for |x| > 1 and |x| < 1
+	{2, Inf(1), Inf(1)},
+	{2, Inf(-1), 0},
+	{0.5, Inf(1), 0},
+	{0.5, Inf(-1), Inf(1)},
+
+	// Pow(+Inf, y)
+	{Inf(1), 2, Inf(1)},
+	{Inf(1), -2, 0},
+
+	// Pow(-Inf, y) = Pow(-0, -y)
+	{Inf(-1), 3, Inf(-1)},  // odd
+	{Inf(-1), 4, Inf(1)},   // even
+	{Inf(-1), -3, Copysign(0, -1)},
+
+	// boundary integer exponents
+	{2, 10, 1024},
+	{2, -10, 1.0 / 1024},
+	{-2, 3, -8},
+	{-2, 4, 16},
+
+	// subnormal base
+	{4.9406564584124654e-324, 1, 4.9406564584124654e-324},
+}
+
+func TestPowSpecialCases(t *testing.T) {
+	for i, tt := range powSpecialCases {
+		got := Pow(tt.x, tt.y)
+		if IsNaN(tt.want) {
+			if !IsNaN(got) {
+				t.Errorf("%d: Pow(%v, %v) = %v; want NaN", i, tt.x, tt.y, got)
+			}
+			continue
+		}
+		if got != tt.want || Signbit(got) != Signbit(tt.want) {
+			t.Errorf("%d: Pow(%v, %v) = %v; want %v", i, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+// maxPowULP is the tightest tolerance TestPowNonInteger and
+// TestPowReferenceVectors allow Pow's fractional-exponent path
+// (exp2Frac/log2) to drift from a correctly rounded result. It's
+// small enough to catch the class of bug where log2's hi+lo split
+// silently lost precision (observed as ~4e7 ULP errors on cases as
+// simple as Pow(4, 0.5)) while still leaving room for Log/Exp2's own
+// rounding.
+const maxPowULP = 8
+
+// ulpDiff returns the number of representable float64 values between
+// a and b.
+func ulpDiff(a, b float64) uint64 {
+	order := func(bits uint64) int64 {
+		if bits&0x8000000000000000 != 0 {
+			return int64(0x8000000000000000 - bits)
+		}
+		return int64(bits)
+	}
+	d := order(refmath.Float64bits(a)) - order(refmath.Float64bits(b))
+	if d < 0 {
+		d = -d
+	}
+	return uint64(d)
+}
+
+func TestPowNonInteger(t *testing.T) {
+	tests := []struct {
+		x, y, want float64
+	}{
+		{4, 0.5, 2},
+		{27, 1.0 / 3, 3},
+		{2, 2, 4},
+	}
+	for i, tt := range tests {
+		got := Pow(tt.x, tt.y)
+		if diff := ulpDiff(got, tt.want); diff > maxPowULP {
+			t.Errorf("%d: Pow(%v, %v) = %v (%d ULP from %v); want within %d ULP", i, tt.x, tt.y, got, diff, tt.want, maxPowULP)
+		}
+	}
+}
+
+// TestPowReferenceVectors checks Pow's fractional-exponent path
+// against the standard library's math.Pow (imported here purely as a
+// trusted reference, under an alias so it doesn't collide with this
+// package's own identifiers) across a spread of bases and exponents
+// spanning many orders of magnitude.
+func TestPowReferenceVectors(t *testing.T) {
+	bases := []float64{
+		0.00001, 0.001, 0.1, 0.5, 0.9999, 1.0001, 1.5, 2, 3, 4, 7, 10,
+		27, 100, 1234.5678, 1e10, 1e-10,
+	}
+	exps := []float64{
+		0.1, 0.2, 0.25, 1.0 / 3, 0.5, 0.75, 0.9, 1.25, 1.5, 2.25, 2.5,
+		3.7, 10.25, -0.5, -1.0 / 3, -2.25, -10.5,
+	}
+
+	for _, x := range bases {
+		for _, y := range exps {
+			want := refmath.Pow(x, y)
+			got := Pow(x, y)
+			if diff := ulpDiff(got, want); diff > maxPowULP {
+				t.Errorf("Pow(%v, %v) = %v (%d ULP from reference %v); want within %d ULP", x, y, got, diff, want, maxPowULP)
+			}
+		}
+	}
+}
+
+func TestPowNegativeBaseNonIntegerExponent(t *testing.T) {
+	if got := Pow(-2, 0.5); !IsNaN(got) {
+		t.Errorf("Pow(-2, 0.5) = %v; want NaN", got)
+	}
+}
+
+// TestPowHugeExponent exercises y values too large for the integer
+// part of the exponent to fit in a uint64, which must not panic or
+// produce garbage from an out-of-range float->int conversion.
+func TestPowHugeExponent(t *testing.T) {
+	const huge = 1e300
+	tests := []struct {
+		x, y, want float64
+	}{
+		{2, huge, Inf(1)},
+		{0.5, huge, 0},
+		{2, -huge, 0},
+		{0.5, -huge, Inf(1)},
+		{1, huge, 1},
+	}
+	for i, tt := range tests {
+		if got := Pow(tt.x, tt.y); got != tt.want {
+			t.Errorf("%d: Pow(%v, %v) = %v; want %v", i, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
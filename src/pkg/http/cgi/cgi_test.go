@@ -0,0 +1,99 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"bytes"
+	"http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordingResponseWriter is a minimal http.ResponseWriter that
+// records what was written to it, so ServeHTTP can be exercised
+// without a real network connection.
+type recordingResponseWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *recordingResponseWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *recordingResponseWriter) Write(p []byte) (int, os.Error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(p)
+}
+
+func (w *recordingResponseWriter) WriteHeader(code int) {
+	w.code = code
+}
+
+// TestHostAndChildAgreeOnEnvironment runs testdata/test.cgi, a
+// script that simply dumps its own environment, through the host
+// side (Handler.ServeHTTP) and then feeds that dumped environment
+// back through the child side (envMap and RequestFromMap) to check
+// that the two sides agree on what a CGI request looks like.
+func TestHostAndChildAgreeOnEnvironment(t *testing.T) {
+	path, err := filepath.Abs("testdata/test.cgi")
+	if err != nil {
+		t.Fatalf("Abs: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Skipf("test.cgi not found: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "http://example.com/myscript/extra/path?a=b", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Host = "example.com"
+	req.RemoteAddr = "1.2.3.4:1234"
+
+	h := &Handler{Path: path, Root: "/myscript"}
+	rw := &recordingResponseWriter{}
+	h.ServeHTTP(rw, req)
+
+	if rw.code != 0 && rw.code != http.StatusOK {
+		t.Fatalf("ServeHTTP returned status %d; body: %s", rw.code, rw.body.String())
+	}
+
+	// The host parses the script's "Content-Type: ..." line into the
+	// response header; the body is the script's `env` dump, one
+	// KEY=VALUE pair per line.
+	if ct := rw.Header().Get("Content-Type"); ct != "text/cgi-environ" {
+		t.Fatalf("response Content-Type = %q; want text/cgi-environ; body: %q", ct, rw.body.String())
+	}
+	env := strings.Split(strings.TrimRight(rw.body.String(), "\n"), "\n")
+
+	child, err := RequestFromMap(envMap(env))
+	if err != nil {
+		t.Fatalf("RequestFromMap: %v", err)
+	}
+	if child.Method != req.Method {
+		t.Errorf("child.Method = %q; want %q", child.Method, req.Method)
+	}
+	if child.Host != req.Host {
+		t.Errorf("child.Host = %q; want %q", child.Host, req.Host)
+	}
+	if child.URL.RawQuery != req.URL.RawQuery {
+		t.Errorf("child.URL.RawQuery = %q; want %q", child.URL.RawQuery, req.URL.RawQuery)
+	}
+	if child.URL.Path != req.URL.Path {
+		t.Errorf("child.URL.Path (from REQUEST_URI) = %q; want %q", child.URL.Path, req.URL.Path)
+	}
+	if want := "1.2.3.4"; child.RemoteAddr != want {
+		t.Errorf("child.RemoteAddr (from REMOTE_ADDR) = %q; want %q", child.RemoteAddr, want)
+	}
+}
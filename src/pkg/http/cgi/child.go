@@ -0,0 +1,168 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements the child side of CGI (the program that's
+// started by a CGI host, e.g. Apache or the Handler in this
+// package).
+
+package cgi
+
+import (
+	"bufio"
+	"fmt"
+	"http"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Request returns the HTTP request as represented in the current
+// environment. This assumes the current program is being run by a
+// CGI host such as Apache, or the Handler in this package.
+func Request() (*http.Request, os.Error) {
+	r, err := RequestFromMap(envMap(os.Environ()))
+	if err != nil {
+		return nil, err
+	}
+	if r.ContentLength > 0 {
+		r.Body = ioutil.NopCloser(io.LimitReader(os.Stdin, r.ContentLength))
+	} else {
+		r.Body = ioutil.NopCloser(strings.NewReader(""))
+	}
+	return r, nil
+}
+
+func envMap(env []string) map[string]string {
+	m := make(map[string]string)
+	for _, kv := range env {
+		if eq := strings.Index(kv, "="); eq != -1 {
+			m[kv[:eq]] = kv[eq+1:]
+		}
+	}
+	return m
+}
+
+// RequestFromMap creates an http.Request from CGI variables. The
+// returned Request's Body field is not populated.
+func RequestFromMap(params map[string]string) (*http.Request, os.Error) {
+	r := new(http.Request)
+	r.Method = params["REQUEST_METHOD"]
+	if r.Method == "" {
+		return nil, os.NewError("cgi: no REQUEST_METHOD in environment")
+	}
+
+	r.Proto = params["SERVER_PROTOCOL"]
+	var ok bool
+	r.ProtoMajor, r.ProtoMinor, ok = http.ParseHTTPVersion(r.Proto)
+	if !ok {
+		return nil, os.NewError("cgi: invalid SERVER_PROTOCOL version")
+	}
+
+	r.Close = true
+	r.Trailer = http.Header{}
+	r.Header = http.Header{}
+
+	r.Host = params["HTTP_HOST"]
+	r.RemoteAddr = params["REMOTE_ADDR"]
+
+	if lenstr := params["CONTENT_LENGTH"]; lenstr != "" {
+		clen, err := strconv.Atoi64(lenstr)
+		if err != nil {
+			return nil, os.NewError("cgi: bad CONTENT_LENGTH in environment: " + lenstr)
+		}
+		r.ContentLength = clen
+	}
+
+	if ct := params["CONTENT_TYPE"]; ct != "" {
+		r.Header.Set("Content-Type", ct)
+	}
+
+	// Copy the HTTP_* environment variables back into HTTP headers.
+	for k, v := range params {
+		if !strings.HasPrefix(k, "HTTP_") || k == "HTTP_HOST" {
+			continue
+		}
+		name := strings.TrimPrefix(k, "HTTP_")
+		name = strings.Title(strings.ToLower(name))
+		name = strings.Replace(name, "_", "-", -1)
+		r.Header.Add(name, v)
+	}
+
+	uriStr := params["REQUEST_URI"]
+	if uriStr == "" {
+		uriStr = params["SCRIPT_NAME"] + params["PATH_INFO"]
+		if q := params["QUERY_STRING"]; q != "" {
+			uriStr += "?" + q
+		}
+	}
+	u, err := url.Parse(uriStr)
+	if err != nil {
+		return nil, os.NewError("cgi: failed to parse REQUEST_URI into a URL: " + err.String())
+	}
+	r.URL = u
+
+	return r, nil
+}
+
+// Serve executes the provided Handler for the CGI request, writing
+// its response as a CGI response (a header block followed by a
+// body) to stdout.  If handler is nil, http.DefaultServeMux is used.
+func Serve(handler http.Handler) os.Error {
+	req, err := Request()
+	if err != nil {
+		return err
+	}
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	rw := &response{
+		req:    req,
+		header: make(http.Header),
+		bufw:   bufio.NewWriter(os.Stdout),
+	}
+	handler.ServeHTTP(rw, req)
+	if err := rw.bufw.Flush(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// response is the child side's http.ResponseWriter: it buffers the
+// header until the first Write or explicit WriteHeader call, then
+// emits it in the "Status:"-then-headers-then-blank-line form CGI
+// expects before streaming the body straight through.
+type response struct {
+	req         *http.Request
+	header      http.Header
+	bufw        *bufio.Writer
+	wroteHeader bool
+}
+
+func (r *response) Header() http.Header {
+	return r.header
+}
+
+func (r *response) Write(p []byte) (n int, err os.Error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	return r.bufw.Write(p)
+}
+
+func (r *response) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	fmt.Fprintf(r.bufw, "Status: %d %s\r\n", code, http.StatusText(code))
+	for k, vv := range r.header {
+		for _, v := range vv {
+			fmt.Fprintf(r.bufw, "%s: %s\r\n", k, v)
+		}
+	}
+	r.bufw.WriteString("\r\n")
+}
@@ -0,0 +1,281 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cgi implements CGI (Common Gateway Interface) as specified
+// in RFC 3875.
+//
+// Note that using CGI means starting a new process to handle every
+// request, which is typically less efficient than using a
+// long-running server. This package is intended primarily for
+// compatibility with existing systems.
+package cgi
+
+import (
+	"bufio"
+	"fmt"
+	"http"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Handler runs an executable in a subprocess with a CGI environment.
+type Handler struct {
+	Path string // path to the CGI executable
+	Root string // root URI prefix of handler or empty for "/"
+
+	Dir        string    // dir to run command in, or empty for os.Getwd()
+	Env        []string  // extra environment variables to set, if any, as "key=value"
+	InheritEnv []string  // environment variables to inherit from host, as "key"
+	Args       []string  // optional arguments to pass to child process
+	Stderr     io.Writer // where to send the subprocess's stderr; nil means os.Stderr
+
+	// PathLocationHandler specifies the root handler to use for
+	// internal redirects when the CGI process returns a local-path
+	// Location header (RFC 3875 section 6.2.2). If nil,
+	// http.DefaultServeMux is used.
+	PathLocationHandler http.Handler
+}
+
+// removeLeadingDuplicates removes leading duplicate environment
+// variables; the later one wins.
+func removeLeadingDuplicates(env []string) (ret []string) {
+	for i, e := range env {
+		found := false
+		if eq := strings.Index(e, "="); eq != -1 {
+			keq := e[:eq+1] // "key="
+			for _, e2 := range env[i+1:] {
+				if strings.HasPrefix(e2, keq) {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			ret = append(ret, e)
+		}
+	}
+	return
+}
+
+func (h *Handler) stderr() io.Writer {
+	if h.Stderr != nil {
+		return h.Stderr
+	}
+	return os.Stderr
+}
+
+// ServeHTTP starts the CGI process, feeds it req as its environment
+// and body, and copies its output back as the HTTP response.
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	root := h.Root
+	if root == "" {
+		root = "/"
+	}
+
+	if len(req.TransferEncoding) > 0 && req.TransferEncoding[0] == "chunked" {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte("Chunked request bodies are not supported by CGI."))
+		return
+	}
+
+	pathInfo := req.URL.Path
+	if root != "/" && strings.HasPrefix(pathInfo, root) {
+		pathInfo = pathInfo[len(root):]
+	}
+
+	port := "80"
+	if idx := strings.LastIndex(req.Host, ":"); idx != -1 {
+		port = req.Host[idx+1:]
+	}
+
+	// RFC 3875 section 4.1.8 requires REMOTE_ADDR to be a bare IP
+	// address, but req.RemoteAddr is normally "ip:port".
+	remoteAddr := req.RemoteAddr
+	if ip, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		remoteAddr = ip
+	}
+
+	env := []string{
+		"SERVER_SOFTWARE=go",
+		"SERVER_NAME=" + req.Host,
+		"SERVER_PROTOCOL=HTTP/1.1",
+		"HTTP_HOST=" + req.Host,
+		"GATEWAY_INTERFACE=CGI/1.1",
+		"REQUEST_METHOD=" + req.Method,
+		"QUERY_STRING=" + req.URL.RawQuery,
+		"REQUEST_URI=" + req.URL.RequestURI(),
+		"PATH_INFO=" + pathInfo,
+		"SCRIPT_NAME=" + root,
+		"SCRIPT_FILENAME=" + h.Path,
+		"REMOTE_ADDR=" + remoteAddr,
+		"REMOTE_HOST=" + remoteAddr,
+		"SERVER_PORT=" + port,
+	}
+
+	if req.TLS != nil {
+		env = append(env, "HTTPS=on")
+	}
+
+	for k, v := range req.Header {
+		k = strings.Map(upperCaseAndUnderscore, k)
+		joinStr := ", "
+		if k == "COOKIE" {
+			joinStr = "; "
+		}
+		env = append(env, "HTTP_"+k+"="+strings.Join(v, joinStr))
+	}
+
+	if req.ContentLength > 0 {
+		env = append(env, "CONTENT_LENGTH="+strconv.Itoa64(req.ContentLength))
+	}
+	if ctype := req.Header.Get("Content-Type"); ctype != "" {
+		env = append(env, "CONTENT_TYPE="+ctype)
+	}
+
+	envPath := os.Getenv("PATH")
+	if envPath == "" {
+		envPath = "/bin:/usr/bin:/usr/local/bin"
+	}
+	env = append(env, "PATH="+envPath)
+
+	for _, e := range h.InheritEnv {
+		if v := os.Getenv(e); v != "" {
+			env = append(env, e+"="+v)
+		}
+	}
+	if h.Env != nil {
+		env = append(env, h.Env...)
+	}
+
+	env = removeLeadingDuplicates(env)
+
+	cwd := h.Dir
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+
+	cmd := &exec.Cmd{
+		Path: h.Path,
+		Args: append([]string{h.Path}, h.Args...),
+		Dir:  cwd,
+		Env:  env,
+	}
+	if req.ContentLength != 0 {
+		cmd.Stdin = req.Body
+	}
+	cmd.Stderr = h.stderr()
+
+	stdoutRead, err := cmd.StdoutPipe()
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		h.stderr().Write([]byte("CGI error: " + err.String()))
+		return
+	}
+
+	if err := cmd.Start(); err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		h.stderr().Write([]byte("CGI error: " + err.String()))
+		return
+	}
+	defer cmd.Wait()
+	defer stdoutRead.Close()
+
+	linebody := bufio.NewReaderSize(stdoutRead, 1024)
+	headers := make(http.Header)
+	statusCode := 0
+	for {
+		line, isPrefix, err := linebody.ReadLine()
+		if isPrefix {
+			rw.WriteHeader(http.StatusInternalServerError)
+			h.stderr().Write([]byte("CGI: long header line from subprocess."))
+			return
+		}
+		if err == os.EOF || len(line) == 0 {
+			break
+		}
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			h.stderr().Write([]byte("CGI: error reading headers: " + err.String()))
+			return
+		}
+		parts := strings.SplitN(string(line), ":", 2)
+		if len(parts) < 2 {
+			h.stderr().Write([]byte("CGI: bogus header line: " + string(line)))
+			continue
+		}
+		header := strings.TrimSpace(parts[0])
+		val := strings.TrimSpace(parts[1])
+		if header == "Status" {
+			if len(val) < 3 {
+				h.stderr().Write([]byte("CGI: bogus status (short): " + val))
+				return
+			}
+			code, err := strconv.Atoi(val[0:3])
+			if err != nil {
+				h.stderr().Write([]byte("CGI: bogus status: " + val))
+				return
+			}
+			statusCode = code
+			continue
+		}
+		headers.Add(header, val)
+	}
+
+	if loc := headers.Get("Location"); loc != "" && strings.HasPrefix(loc, "/") {
+		// A local-path Location header is an internal redirect
+		// (RFC 3875 section 6.2.2): the server re-dispatches the
+		// request itself and the client never sees a redirect.
+		url, err := req.URL.Parse(loc)
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			h.stderr().Write([]byte("CGI: bad local Location: " + loc))
+			return
+		}
+		dupReq := new(http.Request)
+		*dupReq = *req
+		dupReq.URL = url
+		handler := h.PathLocationHandler
+		if handler == nil {
+			handler = http.DefaultServeMux
+		}
+		handler.ServeHTTP(rw, dupReq)
+		return
+	}
+
+	if statusCode == 0 {
+		if headers.Get("Location") != "" {
+			statusCode = http.StatusFound
+		} else {
+			statusCode = http.StatusOK
+		}
+	}
+
+	for k, vv := range headers {
+		for _, v := range vv {
+			rw.Header().Add(k, v)
+		}
+	}
+	rw.WriteHeader(statusCode)
+
+	if _, err := io.Copy(rw, linebody); err != nil {
+		h.stderr().Write([]byte(fmt.Sprintf("CGI: copy error: %v", err)))
+	}
+}
+
+// upperCaseAndUnderscore maps the characters of an HTTP header name
+// to the form used by its CGI meta-variable, e.g. "Accept-Charset"
+// becomes "ACCEPT_CHARSET" for use as "HTTP_ACCEPT_CHARSET".
+func upperCaseAndUnderscore(c int) int {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return c - ('a' - 'A')
+	case c == '-':
+		return '_'
+	}
+	return c
+}
@@ -0,0 +1,333 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP client. See RFC 2616.
+//
+// This is the high-level Client interface.
+// The low-level implementation is in transport.go.
+
+package http
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// A RoundTripper is an interface representing the ability to execute a
+// single HTTP transaction, obtaining the Response for a given Request.
+type RoundTripper interface {
+	// RoundTrip executes a single HTTP transaction, returning
+	// the Response for the request req.
+	RoundTrip(req *Request) (resp *Response, err os.Error)
+}
+
+// RoundTripper is used by Client to send requests and get responses.
+// The default Transport is used if one isn't provided.
+var DefaultTransport RoundTripper = &Transport{}
+
+// A Client is an HTTP client. Its zero value (DefaultClient) is a
+// usable client that uses DefaultTransport.
+//
+// The Client's Transport typically has internal state (cached TCP
+// connections), so Clients should be reused instead of created as
+// needed. Clients are safe for concurrent use by multiple goroutines.
+type Client struct {
+	// Transport specifies the mechanism by which individual
+	// HTTP requests are made. If nil, DefaultTransport is used.
+	Transport RoundTripper
+
+	// CheckRedirect specifies the policy for handling redirects.
+	// If CheckRedirect is not nil, the client calls it before
+	// following an HTTP redirect. The arguments req and via are
+	// the upcoming request and the requests made already, oldest
+	// first. If CheckRedirect returns an error, the Client's Get
+	// method returns both the previous Response and
+	// CheckRedirect's error (wrapped in a url.Error) instead of
+	// issuing the Request req.
+	//
+	// If CheckRedirect is nil, the Client uses its default policy,
+	// which is to stop after 10 consecutive requests.
+	CheckRedirect func(req *Request, via []*Request) os.Error
+
+	// Jar specifies the cookie jar to consult for every outgoing
+	// request and to record the Set-Cookie headers of every
+	// incoming response. If Jar is nil, cookies are not sent and
+	// Set-Cookie headers are ignored.
+	Jar CookieJar
+}
+
+// A CookieJar manages storage and use of cookies in HTTP requests.
+//
+// Implementations of CookieJar must be safe for concurrent use by
+// multiple goroutines.
+type CookieJar interface {
+	// SetCookies handles the receipt of the cookies in a reply for the
+	// given URL. It may or may not choose to save the cookies,
+	// depending on the jar's policy and implementation.
+	SetCookies(u *url.URL, cookies []*Cookie)
+
+	// Cookies returns the cookies to send in a request for the given URL.
+	// It is up to the implementation to honor the standard cookie use
+	// restrictions such as in RFC 6265.
+	Cookies(u *url.URL) []*Cookie
+}
+
+// DefaultClient is the default Client and is used by Get, Head, and Post.
+var DefaultClient = &Client{}
+
+// send issues an HTTP request, following any Jar cookies and
+// resolving the request against the Client's Transport.
+func send(req *Request, t RoundTripper, jar CookieJar) (resp *Response, err os.Error) {
+	if req.URL == nil {
+		return nil, os.NewError("http: nil Request.URL")
+	}
+
+	if jar != nil {
+		for _, cookie := range jar.Cookies(req.URL) {
+			req.AddCookie(cookie)
+		}
+	}
+
+	if t == nil {
+		t = DefaultTransport
+		if t == nil {
+			return nil, os.NewError("http: no Client.Transport or DefaultTransport")
+		}
+	}
+
+	resp, err = t.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if jar != nil {
+		if rc := resp.SetCookie; len(rc) > 0 {
+			jar.SetCookies(req.URL, rc)
+		}
+	}
+
+	return resp, nil
+}
+
+// Do sends an HTTP request and returns an HTTP response, following
+// policy (e.g. redirects, cookies, auth) as configured on the client.
+//
+// A non-nil error is returned if caused by client policy (such as
+// CheckRedirect), or if there was an HTTP protocol error. A non-nil
+// response always contains a non-nil resp.Body.
+//
+// Callers should close resp.Body when done reading from it. If
+// resp.Body is not closed, the Client's underlying RoundTripper may
+// not be able to re-use a persistent TCP connection to the server
+// for a subsequent "keep-alive" request.
+//
+// On a 307 redirect of a request with a non-nil Body, Do reuses the
+// same Body reader rather than replaying its original bytes; since
+// the prior hop's RoundTrip has already drained it, the redirected
+// request is sent with an empty body instead of a true repeat of the
+// original request.
+func (c *Client) Do(req *Request) (resp *Response, err os.Error) {
+	return c.doFollowingRedirects(req)
+}
+
+// maxRedirects is the default limit on the number of requests in one
+// chain of redirects: once that many have been sent, the Client stops
+// instead of following the next redirect.
+const maxRedirects = 10
+
+// doFollowingRedirects implements the redirect and cross-host auth
+// stripping policy used by Get, Head, Post, and PostForm.
+func (c *Client) doFollowingRedirects(ireq *Request) (resp *Response, err os.Error) {
+	var base *url.URL
+	var via []*Request
+
+	req := ireq
+	urlStr := ""      // next relative or absolute URL to fetch (after first request)
+	redirectCode := 0 // status code of the response that produced urlStr
+	for redirect := 0; ; redirect++ {
+		if redirect != 0 {
+			nreq := new(Request)
+			nreq.Method = req.Method
+			// req.Body is whatever io.Reader the previous hop's
+			// RoundTrip already drained sending the prior request, so
+			// it has nothing left to give; 307 is documented to repeat
+			// a POST/PUT's body, but this Client can't actually
+			// replay it without a way to produce a fresh Reader (a
+			// callers-supplied body-producing func, say), so a 307
+			// retry of a request with a body currently resends an
+			// empty one instead of the original bytes.
+			nreq.Body = req.Body
+			nreq.ContentLength = req.ContentLength
+			switch redirectCode {
+			case StatusMovedPermanently, StatusFound, StatusSeeOther:
+				// RFC 2616 10.3 permits (and in practice requires)
+				// rewriting a POST/PUT to a bodyless GET on these
+				// three codes; 307 must repeat the original request
+				// unchanged, so it falls through untouched.
+				if req.Method == "POST" || req.Method == "PUT" {
+					nreq.Method = "GET"
+					nreq.Body = nil
+					nreq.ContentLength = 0
+				}
+			}
+			nreq.Header = make(Header)
+			nreq.URL, err = base.Parse(urlStr)
+			if err != nil {
+				break
+			}
+			if len(via) > 0 {
+				// Copy the initial request's headers, minus any
+				// sensitive ones that shouldn't follow the client
+				// across hosts.
+				for k, v := range ireq.Header {
+					if shouldCopyHeaderOnRedirect(k, via[0].URL, nreq.URL) {
+						nreq.Header[k] = v
+					}
+				}
+			}
+			// The hop-count limit is enforced by checkRedirect (see
+			// defaultCheckRedirect) rather than here, so a custom
+			// CheckRedirect always gets a chance to run, even on
+			// what would otherwise be the final hop.
+			if err = c.checkRedirect(nreq, via); err != nil {
+				break
+			}
+			req = nreq
+		}
+
+		urlStr = req.URL.String()
+		if resp, err = send(req, c.Transport, c.Jar); err != nil {
+			break
+		}
+
+		if shouldRedirect(resp.StatusCode) {
+			resp.Body.Close()
+			if urlStr = resp.Header.Get("Location"); urlStr == "" {
+				err = os.NewError("http: redirect not returning location")
+				break
+			}
+			base = req.URL
+			via = append(via, req)
+			redirectCode = resp.StatusCode
+			continue
+		}
+		return
+	}
+
+	err = &url.Error{Op: ireq.Method, URL: urlStr, Err: err}
+	return
+}
+
+func (c *Client) checkRedirect(req *Request, via []*Request) os.Error {
+	fn := c.CheckRedirect
+	if fn == nil {
+		fn = defaultCheckRedirect
+	}
+	return fn(req, via)
+}
+
+func defaultCheckRedirect(req *Request, via []*Request) os.Error {
+	if len(via) >= maxRedirects {
+		return os.NewError("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// shouldRedirect reports whether the given response status code
+// counts as a redirect that (*Client).Do should follow.
+func shouldRedirect(statusCode int) bool {
+	switch statusCode {
+	case StatusMovedPermanently, StatusFound, StatusSeeOther, StatusTemporaryRedirect:
+		return true
+	}
+	return false
+}
+
+// shouldCopyHeaderOnRedirect reports whether a header with key
+// headerKey should be copied onto a redirected request, bound for
+// dest, having originated from req's original URL.
+func shouldCopyHeaderOnRedirect(headerKey string, from, dest *url.URL) bool {
+	switch CanonicalHeaderKey(headerKey) {
+	case "Authorization", "Www-Authenticate", "Cookie", "Cookie2":
+		// Permit sending auth/cookie headers only back to the
+		// exact same host they came from.
+		return from != nil && dest != nil && from.Host == dest.Host
+	}
+	return true
+}
+
+// Get issues a GET to the specified URL. If the response is one of
+// the following redirect codes, Get follows the redirect after
+// calling the Client's CheckRedirect function.
+//
+//     301 (Moved Permanently)
+//     302 (Found)
+//     303 (See Other)
+//     307 (Temporary Redirect)
+//
+// Caller should close r.Body when done reading from it.
+func (c *Client) Get(url string) (r *Response, err os.Error) {
+	req, err := NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.doFollowingRedirects(req)
+}
+
+// Head issues a HEAD to the specified URL. If the response is one
+// of the following redirect codes, Head follows the redirect after
+// calling the Client's CheckRedirect function.
+//
+//     301 (Moved Permanently)
+//     302 (Found)
+//     303 (See Other)
+//     307 (Temporary Redirect)
+func (c *Client) Head(url string) (r *Response, err os.Error) {
+	req, err := NewRequest("HEAD", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.doFollowingRedirects(req)
+}
+
+// Post issues a POST to the specified URL.
+//
+// Caller should close r.Body when done reading from it.
+func (c *Client) Post(url string, bodyType string, body io.Reader) (r *Response, err os.Error) {
+	req, err := NewRequest("POST", url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", bodyType)
+	return c.doFollowingRedirects(req)
+}
+
+// PostForm issues a POST to the specified URL, with data's keys and
+// values URL-encoded as the request body.
+//
+// Caller should close r.Body when done reading from it.
+func (c *Client) PostForm(urlStr string, data url.Values) (r *Response, err os.Error) {
+	return c.Post(urlStr, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+}
+
+// Get, Head, Post, and PostForm are convenience wrappers around
+// DefaultClient's methods.
+
+func Get(url string) (r *Response, err os.Error) {
+	return DefaultClient.Get(url)
+}
+
+func Head(url string) (r *Response, err os.Error) {
+	return DefaultClient.Head(url)
+}
+
+func Post(url string, bodyType string, body io.Reader) (r *Response, err os.Error) {
+	return DefaultClient.Post(url, bodyType, body)
+}
+
+func PostForm(url string, data url.Values) (r *Response, err os.Error) {
+	return DefaultClient.PostForm(url, data)
+}
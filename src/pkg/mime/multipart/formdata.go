@@ -0,0 +1,159 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+)
+
+// Form is a parsed multipart form.
+// Its File parts are stored either in memory or on disk,
+// and are accessible via the *FileHeader's Open method.
+// Its Value parts are stored as strings.
+// Both are keyed by field name.
+type Form struct {
+	Value map[string][]string
+	File  map[string][]*FileHeader
+}
+
+// RemoveAll removes any temporary files associated with a Form.
+func (f *Form) RemoveAll() os.Error {
+	var err os.Error
+	for _, fhs := range f.File {
+		for _, fh := range fhs {
+			if fh.tmpfile != "" {
+				e := os.Remove(fh.tmpfile)
+				if e != nil && err == nil {
+					err = e
+				}
+			}
+		}
+	}
+	return err
+}
+
+// A FileHeader describes a file part of a multipart request.
+type FileHeader struct {
+	Filename string
+	Header   textproto.MIMEHeader
+
+	content []byte
+	tmpfile string
+}
+
+// Open returns the contents of the file part, either from memory
+// or from a temporary file on disk.
+func (fh *FileHeader) Open() (File, os.Error) {
+	if fh.content != nil {
+		return &sectionReadCloser{io.NewSectionReader(bytes.NewReader(fh.content), 0, int64(len(fh.content)))}, nil
+	}
+	return os.Open(fh.tmpfile)
+}
+
+// File is an interface to access the file part of a multipart
+// message. Its contents may be either stored in memory or on disk.
+// If stored on disk, the File's underlying concrete type will be an
+// *os.File.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+type sectionReadCloser struct {
+	*io.SectionReader
+}
+
+func (rc *sectionReadCloser) Close() os.Error {
+	return nil
+}
+
+// ReadForm parses an entire multipart message whose parts have a
+// Content-Disposition of "form-data". It stores up to maxMemory
+// bytes of the parts' combined contents (values and files alike) in
+// memory, spilling anything beyond that shared budget for a file
+// part to a temporary file on disk.
+func (r *multiReader) ReadForm(maxMemory int64) (f *Form, err os.Error) {
+	form := &Form{
+		Value: make(map[string][]string),
+		File:  make(map[string][]*FileHeader),
+	}
+	defer func() {
+		if err != nil {
+			form.RemoveAll()
+		}
+	}()
+
+	// remaining is the in-memory budget shared across every value
+	// and file part; it only ever shrinks as parts are read.
+	remaining := maxMemory
+	for {
+		p, err := r.NextPart()
+		if err == os.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+		filename := p.FileName()
+
+		toRead := remaining + 1
+		if toRead < 0 {
+			toRead = 0
+		}
+		var b bytes.Buffer
+		n, err := io.CopyN(&b, p, toRead)
+		if err != nil && err != os.EOF {
+			return nil, err
+		}
+		remaining -= n
+
+		if filename == "" {
+			// value, store in memory
+			if remaining < 0 {
+				return nil, os.NewError("multipart: message too large")
+			}
+			form.Value[name] = append(form.Value[name], b.String())
+			continue
+		}
+
+		// file, store in memory or on disk
+		fh := &FileHeader{
+			Filename: filename,
+			Header:   p.Header,
+		}
+		if remaining < 0 {
+			file, err := ioutil.TempFile("", "multipart-")
+			if err != nil {
+				return nil, err
+			}
+			var copyErr os.Error
+			_, copyErr = io.Copy(file, io.MultiReader(&b, p))
+			if cerr := file.Close(); cerr != nil && copyErr == nil {
+				copyErr = cerr
+			}
+			if copyErr != nil {
+				os.Remove(file.Name())
+				return nil, copyErr
+			}
+			fh.tmpfile = file.Name()
+		} else {
+			fh.content = b.Bytes()
+		}
+		form.File[name] = append(form.File[name], fh)
+	}
+
+	return form, nil
+}
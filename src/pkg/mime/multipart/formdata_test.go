@@ -0,0 +1,141 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadForm(t *testing.T) {
+	testBody := strings.Replace(`
+--MyBoundary
+Content-Disposition: form-data; name="text"
+
+hello world
+--MyBoundary
+Content-Disposition: form-data; name="file"; filename="foo.txt"
+Content-Type: text/plain
+
+the file contents
+--MyBoundary--
+`, "\n", "\r\n", -1)
+
+	r := NewReader(strings.NewReader(testBody), "MyBoundary")
+	form, err := r.ReadForm(64)
+	if err != nil {
+		t.Fatalf("ReadForm: %v", err)
+	}
+	defer form.RemoveAll()
+
+	if g, e := form.Value["text"], []string{"hello world"}; len(g) != 1 || g[0] != e[0] {
+		t.Errorf("Value[text] = %v; want %v", g, e)
+	}
+
+	fhs := form.File["file"]
+	if len(fhs) != 1 {
+		t.Fatalf("expected 1 file; got %d", len(fhs))
+	}
+	fh := fhs[0]
+	if fh.Filename != "foo.txt" {
+		t.Errorf("Filename = %q; want foo.txt", fh.Filename)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	got, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("reading file contents: %v", err)
+	}
+	if string(got) != "the file contents" {
+		t.Errorf("file contents = %q", got)
+	}
+}
+
+func TestReadFormSpillsToDisk(t *testing.T) {
+	big := strings.Repeat("a", 1<<20)
+	testBody := strings.Replace(`
+--MyBoundary
+Content-Disposition: form-data; name="file"; filename="big.txt"
+Content-Type: text/plain
+
+`+big+`
+--MyBoundary--
+`, "\n", "\r\n", -1)
+
+	r := NewReader(strings.NewReader(testBody), "MyBoundary")
+	form, err := r.ReadForm(1024)
+	if err != nil {
+		t.Fatalf("ReadForm: %v", err)
+	}
+	defer form.RemoveAll()
+
+	fh := form.File["file"][0]
+	if fh.tmpfile == "" {
+		t.Errorf("expected large file part to spill to disk")
+	}
+	if _, err := os.Stat(fh.tmpfile); err != nil {
+		t.Errorf("temp file missing: %v", err)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	got, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+	if string(got) != big {
+		t.Errorf("file contents mismatched (len %d vs %d)", len(got), len(big))
+	}
+
+	tmpfile := fh.tmpfile
+	if err := form.RemoveAll(); err != nil {
+		t.Errorf("RemoveAll: %v", err)
+	}
+	if _, err := os.Stat(tmpfile); err == nil {
+		t.Errorf("temp file %s still exists after RemoveAll", tmpfile)
+	}
+}
+
+// TestReadFormSharedMemoryBudget verifies that the maxMemory budget
+// is shared across all parts rather than reset per file: of two files
+// that are each individually under maxMemory but together exceed it,
+// at least one must end up on disk rather than in memory.
+func TestReadFormSharedMemoryBudget(t *testing.T) {
+	const maxMemory = 1024
+	part := strings.Repeat("a", 700)
+	testBody := strings.Replace(`
+--MyBoundary
+Content-Disposition: form-data; name="file1"; filename="one.txt"
+Content-Type: text/plain
+
+`+part+`
+--MyBoundary
+Content-Disposition: form-data; name="file2"; filename="two.txt"
+Content-Type: text/plain
+
+`+part+`
+--MyBoundary--
+`, "\n", "\r\n", -1)
+
+	r := NewReader(strings.NewReader(testBody), "MyBoundary")
+	form, err := r.ReadForm(maxMemory)
+	if err != nil {
+		t.Fatalf("ReadForm: %v", err)
+	}
+	defer form.RemoveAll()
+
+	fh1 := form.File["file1"][0]
+	fh2 := form.File["file2"][0]
+	if fh1.tmpfile == "" && fh2.tmpfile == "" {
+		t.Errorf("expected at least one of the two %d-byte parts to spill to disk under a shared %d-byte budget", len(part), maxMemory)
+	}
+}
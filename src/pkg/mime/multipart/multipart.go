@@ -0,0 +1,353 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The multipart package implements MIME multipart parsing, as
+// defined in RFC 2046.
+//
+// The implementation is sufficient for HTTP (RFC 2388) and the multipart
+// bodies generated by popular browsers.
+package multipart
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// peekBufferSize bounds both the size of multiReader's internal
+// bufio.Reader and the number of bytes a Part will look ahead for a
+// boundary line before giving up and returning what it has.
+const peekBufferSize = 4096
+
+var emptyParams = make(map[string]string)
+
+// A Part represents a single part in a multipart body.
+type Part struct {
+	// The headers of the body, if any, with the keys canonicalized
+	// in the same fashion that the Go http package canonicalizes
+	// headers. For example, "foo-bar" changes case to "Foo-Bar".
+	Header textproto.MIMEHeader
+
+	buffer            *bufio.Reader
+	mr                *multiReader
+	disposition       string
+	dispositionParams map[string]string
+
+	// atEOF is set once partReader has seen this part's closing
+	// boundary; the boundary itself is left in the stream for NextPart
+	// to read. It must be sticky: the bufio.Reader wrapping partReader
+	// only reports an os.EOF once and then forgets it on the next
+	// Read, so without this flag a Read made after NextPart has moved
+	// on would fall through to partReader.Read and start consuming the
+	// next part.
+	atEOF bool
+}
+
+// FormName returns the name parameter if p has a Content-Disposition
+// of type "form-data".  Otherwise it returns the empty string.
+func (p *Part) FormName() string {
+	// See RFC 2183 and RFC 2045.
+	v := p.Header.Get("Content-Disposition")
+	if v == "" {
+		return ""
+	}
+	d, params, err := mime.ParseMediaType(v)
+	if err != nil || d != "form-data" {
+		return ""
+	}
+	return params["name"]
+}
+
+// FileName returns the filename parameter of the Part's
+// Content-Disposition header.
+func (p *Part) FileName() string {
+	if p.dispositionParams == nil {
+		p.parseContentDisposition()
+	}
+	return p.dispositionParams["filename"]
+}
+
+func (p *Part) parseContentDisposition() {
+	v := p.Header.Get("Content-Disposition")
+	var err os.Error
+	p.disposition, p.dispositionParams, err = mime.ParseMediaType(v)
+	if err != nil {
+		p.dispositionParams = emptyParams
+	}
+}
+
+// NewReader creates a new multipart Reader reading from r using the
+// given MIME boundary.
+//
+// The boundary is usually obtained from the "boundary" parameter of
+// the message's "Content-Type" header.  Use mime.ParseMediaType to
+// parse such headers.
+//
+// If r is itself a *Part whose own Content-Type is "multipart/*",
+// NewReader returns a Reader scoped to that Part's body: it stops at
+// the inner closing boundary without reading past it, so the outer
+// Reader that produced r is left exactly where the nested message
+// ended and can continue walking its own parts afterward. This is
+// how a multipart/mixed part containing, say, a nested
+// multipart/alternative is read.
+func NewReader(reader io.Reader, boundary string) Reader {
+	b := []byte("\r\n--" + boundary + "--")
+
+	// If reader is a Part, read directly from its buffer instead of
+	// wrapping it in a second bufio.Reader. A Part's Read already
+	// refuses to cross its own enclosing boundary, so reading from
+	// its buffer directly ensures this nested Reader can't consume
+	// bytes belonging to the outer message.
+	var br *bufio.Reader
+	if p, ok := reader.(*Part); ok {
+		br = p.buffer
+	} else {
+		br = bufio.NewReaderSize(reader, peekBufferSize)
+	}
+
+	return &multiReader{
+		bufReader: br,
+
+		nl:               b[:2],
+		nlDashBoundary:   b[:len(b)-2],
+		dashBoundaryDash: b[2:],
+		dashBoundary:     b[2 : len(b)-2],
+	}
+}
+
+// Reader is an iterator over parts in a MIME multipart body.
+// Reader's underlying parser consumes its input as needed. Seeking
+// isn't supported.
+type Reader interface {
+	// NextPart returns the next part in the multipart or an
+	// os.EOF error when there are no more parts.
+	NextPart() (*Part, os.Error)
+
+	// ReadForm parses an entire multipart message whose parts have
+	// a Content-Disposition of "form-data", as generated by an
+	// HTML form submission.
+	ReadForm(maxMemory int64) (*Form, os.Error)
+}
+
+type multiReader struct {
+	bufReader *bufio.Reader
+
+	currentPart *Part
+	partsRead   int
+
+	nl               []byte // "\r\n" or "\n" (set after seeing first boundary line)
+	nlDashBoundary   []byte // nl + "--boundary"
+	dashBoundaryDash []byte // "--boundary--"
+	dashBoundary     []byte // "--boundary"
+}
+
+func (mr *multiReader) NextPart() (*Part, os.Error) {
+	if mr.currentPart != nil {
+		// Discard anything the caller didn't read from the previous
+		// part so we're positioned at its closing boundary.
+		io.Copy(ioutil.Discard, mr.currentPart)
+	}
+
+	expectNewPart := false
+	for {
+		line, err := mr.bufReader.ReadSlice('\n')
+
+		if err == bufio.ErrBufferFull && bytes.Equal(line, mr.nlDashBoundary) {
+			// "--boundary" line straddled the buffer; read more.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("multipart: NextPart: %v", err)
+		}
+
+		if mr.isBoundaryDelimiterLine(line) {
+			mr.partsRead++
+			bp, err := newPart(mr)
+			if err != nil {
+				return nil, err
+			}
+			mr.currentPart = bp
+			return bp, nil
+		}
+
+		if mr.isFinalBoundary(line) {
+			return nil, os.EOF
+		}
+
+		if expectNewPart {
+			return nil, fmt.Errorf("multipart: expecting a new Part; got line %q", string(line))
+		}
+
+		if mr.partsRead == 0 {
+			// Skip over leading garbage, looking for the first boundary.
+			continue
+		}
+
+		// Consume the separator between the previous part's body
+		// and the boundary line that must follow it.
+		if bytes.Equal(line, mr.nl) {
+			expectNewPart = true
+			continue
+		}
+
+		return nil, fmt.Errorf("multipart: unexpected line in NextPart(): %q", string(line))
+	}
+	panic("unreachable")
+}
+
+// isFinalBoundary reports whether line is the final boundary line
+// indicating that all parts are over.
+// It matches `^--boundary--[ \t]*(\r\n)?$`
+func (mr *multiReader) isFinalBoundary(line []byte) bool {
+	if !bytes.HasPrefix(line, mr.dashBoundaryDash) {
+		return false
+	}
+	rest := line[len(mr.dashBoundaryDash):]
+	rest = skipLWSPChar(rest)
+	return len(rest) == 0 || bytes.Equal(rest, mr.nl)
+}
+
+func (mr *multiReader) isBoundaryDelimiterLine(line []byte) (ret bool) {
+	// http://tools.ietf.org/html/rfc2046#section-5.1
+	//   The boundary delimiter line is then defined as a line
+	//   consisting entirely of two hyphen characters ("-",
+	//   decimal value 45) followed by the boundary parameter
+	//   value from the Content-Type header field, optional linear
+	//   whitespace, and a terminating CRLF.
+	if !bytes.HasPrefix(line, mr.dashBoundary) {
+		return false
+	}
+	rest := line[len(mr.dashBoundary):]
+	rest = skipLWSPChar(rest)
+
+	// On the first part, accept lines ending in "\n" instead of "\r\n".
+	if mr.partsRead == 0 && len(rest) == 1 && rest[0] == '\n' {
+		mr.nl = mr.nl[1:]
+		mr.nlDashBoundary = mr.nlDashBoundary[1:]
+		return true
+	}
+	return bytes.Equal(rest, mr.nl)
+}
+
+// skipLWSPChar returns b with leading spaces and tabs removed.
+// RFC 822 defines:
+//    LWSP-char = SPACE / HTAB
+func skipLWSPChar(b []byte) []byte {
+	for len(b) > 0 && (b[0] == ' ' || b[0] == '\t') {
+		b = b[1:]
+	}
+	return b
+}
+
+func onlyHorizontalWhitespace(s []byte) bool {
+	for _, b := range s {
+		if b != ' ' && b != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// cteHeader is the header that, when set to "quoted-printable", is
+// decoded transparently by Part.Read and hidden from Part.Header.
+const cteHeader = "Content-Transfer-Encoding"
+
+func newPart(mr *multiReader) (*Part, os.Error) {
+	bp := &Part{mr: mr}
+	if err := bp.populateHeaders(); err != nil {
+		return nil, err
+	}
+	bp.buffer = bufio.NewReaderSize(partReader{bp}, peekBufferSize)
+
+	if strings.ToLower(bp.Header.Get(cteHeader)) == "quoted-printable" {
+		bp.Header.Del(cteHeader)
+		bp.buffer = bufio.NewReaderSize(newQuotedPrintableReader(bp.buffer), peekBufferSize)
+	}
+	return bp, nil
+}
+
+func (bp *Part) populateHeaders() os.Error {
+	tr := textproto.NewReader(bp.mr.bufReader)
+	header, err := tr.ReadMIMEHeader()
+	if err == nil {
+		bp.Header = header
+	}
+	return err
+}
+
+// Read reads the body of a part, after its headers and before the
+// next part (if any) begins.
+func (bp *Part) Read(d []byte) (n int, err os.Error) {
+	return bp.buffer.Read(d)
+}
+
+// Close is a no-op; it exists only so Part satisfies io.ReadCloser,
+// which is convenient for callers that accept either a Part or the
+// body of an ordinary HTTP response.
+func (bp *Part) Close() os.Error {
+	return nil
+}
+
+// partReader is the raw byte source behind a Part's buffered Reader:
+// it reads straight from the multiReader's underlying stream, taking
+// care never to read past the boundary that ends the current part.
+type partReader struct {
+	p *Part
+}
+
+func (pr partReader) Read(d []byte) (n int, err os.Error) {
+	p := pr.p
+	if p.atEOF {
+		return 0, os.EOF
+	}
+	mr := p.mr
+
+	reqLen := len(d)
+	if reqLen < len(mr.nlDashBoundary) {
+		// Always look far enough ahead to recognize a whole boundary.
+		reqLen = len(mr.nlDashBoundary)
+	}
+	if reqLen > peekBufferSize {
+		reqLen = peekBufferSize
+	}
+	peek, peekErr := mr.bufReader.Peek(reqLen)
+
+	if idx := bytes.Index(peek, mr.nlDashBoundary); idx != -1 {
+		if idx == 0 {
+			p.atEOF = true
+			return 0, os.EOF
+		}
+		return mr.bufReader.Read(d[:idx])
+	}
+
+	if peekErr == os.EOF {
+		// No more data will ever arrive, so this is as much of the
+		// boundary as we're ever going to see: it isn't one.
+		if len(peek) == 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if len(peek) > len(d) {
+			peek = peek[:len(d)]
+		}
+		return mr.bufReader.Read(d[:len(peek)])
+	}
+	if peekErr != nil {
+		return 0, peekErr
+	}
+
+	// The boundary could still start in the unpeeked tail of the
+	// stream; never hand out the last len(nlDashBoundary)-1 bytes of
+	// what we've seen, since they might be its unconfirmed prefix.
+	safe := len(peek) - (len(mr.nlDashBoundary) - 1)
+	if safe <= 0 {
+		safe = 1
+	}
+	return mr.bufReader.Read(d[:safe])
+}
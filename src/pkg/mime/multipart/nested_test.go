@@ -0,0 +1,86 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// nestedMIME is a multipart/mixed message containing a nested
+// multipart/alternative part, two levels deep, followed by a
+// trailing top-level part.
+const nestedMIME = "" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"plain body\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html\r\n" +
+	"\r\n" +
+	"<p>html body</p>\r\n" +
+	"--inner--\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"trailing part\r\n" +
+	"--outer--\r\n"
+
+func TestNestedMultipart(t *testing.T) {
+	outer := NewReader(strings.NewReader(nestedMIME), "outer")
+
+	nestedPart, err := outer.NextPart()
+	if err != nil {
+		t.Fatalf("outer.NextPart: %v", err)
+	}
+	if ct := nestedPart.Header.Get("Content-Type"); !strings.HasPrefix(ct, "multipart/alternative") {
+		t.Fatalf("nested part Content-Type = %q", ct)
+	}
+
+	inner := NewReader(nestedPart, "inner")
+
+	p1, err := inner.NextPart()
+	if err != nil {
+		t.Fatalf("inner.NextPart #1: %v", err)
+	}
+	b1, _ := ioutil.ReadAll(p1)
+	if string(b1) != "plain body" {
+		t.Errorf("leaf 1 body = %q", b1)
+	}
+
+	p2, err := inner.NextPart()
+	if err != nil {
+		t.Fatalf("inner.NextPart #2: %v", err)
+	}
+	b2, _ := ioutil.ReadAll(p2)
+	if string(b2) != "<p>html body</p>" {
+		t.Errorf("leaf 2 body = %q", b2)
+	}
+
+	if _, err := inner.NextPart(); err != os.EOF {
+		t.Fatalf("inner.NextPart #3: got %v; want os.EOF", err)
+	}
+
+	// The outer reader must still be positioned right after the
+	// nested message, able to read the trailing top-level part.
+	trailing, err := outer.NextPart()
+	if err != nil {
+		t.Fatalf("outer.NextPart (trailing): %v", err)
+	}
+	bt, _ := ioutil.ReadAll(trailing)
+	if string(bt) != "trailing part" {
+		t.Errorf("trailing part body = %q", bt)
+	}
+
+	if _, err := outer.NextPart(); err != os.EOF {
+		t.Fatalf("outer.NextPart (final): got %v; want os.EOF", err)
+	}
+}
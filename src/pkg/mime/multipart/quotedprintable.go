@@ -0,0 +1,106 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// newQuotedPrintableReader returns a reader that decodes
+// quoted-printable data (RFC 2045 section 6.7) read from r.
+func newQuotedPrintableReader(r *bufio.Reader) io.Reader {
+	return &quotedPrintableReader{r: r}
+}
+
+type quotedPrintableReader struct {
+	r    *bufio.Reader
+	rerr os.Error // last error from r
+}
+
+func fromHex(b byte) (byte, os.Error) {
+	switch {
+	case b >= '0' && b <= '9':
+		return b - '0', nil
+	case b >= 'A' && b <= 'F':
+		return b - 'A' + 10, nil
+	}
+	return 0, os.NewError("mime: invalid quoted-printable hex byte")
+}
+
+// readHexByte decodes the two hex digits following an already-
+// consumed '=' and returns the byte they represent.
+func (q *quotedPrintableReader) readHexByte() (b byte, err os.Error) {
+	var hb [2]byte
+	for i := range hb {
+		hb[i], err = q.r.ReadByte()
+		if err != nil {
+			if err == os.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+	}
+	rv1, err := fromHex(hb[0])
+	if err != nil {
+		return 0, err
+	}
+	rv2, err := fromHex(hb[1])
+	if err != nil {
+		return 0, err
+	}
+	return rv1<<4 | rv2, nil
+}
+
+func (q *quotedPrintableReader) Read(p []byte) (n int, err os.Error) {
+	for len(p) > 0 {
+		if q.rerr != nil {
+			return n, q.rerr
+		}
+		var b byte
+		b, q.rerr = q.r.ReadByte()
+		if q.rerr != nil {
+			return n, q.rerr
+		}
+		switch {
+		case b == '=':
+			b1, err := q.r.Peek(1)
+			if err == nil && len(b1) == 1 && (b1[0] == '\n' || b1[0] == '\r') {
+				// A soft line break: "=\n" or "=\r\n". Consume it
+				// and keep decoding, without emitting any bytes.
+				b2, _ := q.r.ReadByte()
+				if b2 == '\r' {
+					if peek, perr := q.r.Peek(1); perr == nil && len(peek) == 1 && peek[0] == '\n' {
+						q.r.ReadByte()
+					}
+				}
+				continue
+			}
+			if err != nil {
+				// A bare "=" with nothing at all following it (true
+				// EOF, not even a soft line break): drop it rather
+				// than treating it as a truncated escape sequence.
+				continue
+			}
+			hb, herr := q.readHexByte()
+			if herr != nil {
+				q.rerr = herr
+				return n, q.rerr
+			}
+			p[0] = hb
+		case b == '\t' || b == ' ':
+			// Trailing whitespace is only legal immediately before a
+			// line break; since we don't look ahead across lines,
+			// pass it through unchanged like any other byte.
+			p[0] = b
+		default:
+			p[0] = b
+		}
+		p = p[1:]
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bufio"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestQuotedPrintable(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"foo bar", "foo bar"},
+		{"foo=3Dbar", "foo=bar"},
+		{"foo=\r\nbar", "foobar"}, // soft line break
+		{"foo=\nbar", "foobar"},   // bare soft line break
+		{"foo=", "foo"},           // bare "=" at true EOF, dropped
+	}
+	for i, tt := range tests {
+		r := newQuotedPrintableReader(bufio.NewReader(strings.NewReader(tt.in)))
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("%d. ReadAll(%q) error: %v", i, tt.in, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%d. ReadAll(%q) = %q; want %q", i, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuotedPrintableBadHex(t *testing.T) {
+	r := newQuotedPrintableReader(bufio.NewReader(strings.NewReader("foo=3dbar")))
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Errorf("expected an error decoding lowercase hex escape")
+	}
+}
+
+func TestQuotedPrintableSoftBreakAcrossBuffer(t *testing.T) {
+	// A long run of 'a's followed by a soft line break and more
+	// data, long enough to land the "=\r\n" across an internal
+	// bufio.Reader refill boundary.
+	in := strings.Repeat("a", 8000) + "=\r\n" + "bcd"
+	want := strings.Repeat("a", 8000) + "bcd"
+	r := newQuotedPrintableReader(bufio.NewReader(strings.NewReader(in)))
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll error: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("got %d bytes; want %d bytes matching", len(got), len(want))
+	}
+}
+
+func TestPartQuotedPrintableDecoding(t *testing.T) {
+	testBody := strings.Replace(`
+This is a multi-part message.
+--BOUNDARY
+Content-Transfer-Encoding: quoted-printable
+foo-bar: baz
+
+This=20is=20decoded
+--BOUNDARY--
+`, "\n", "\r\n", -1)
+	r := NewReader(strings.NewReader(testBody), "BOUNDARY")
+	part, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	if _, ok := part.Header["Content-Transfer-Encoding"]; ok {
+		t.Errorf("Content-Transfer-Encoding header should be hidden")
+	}
+	got, err := ioutil.ReadAll(part)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(got) != "This is decoded" {
+		t.Errorf("got %q", got)
+	}
+}
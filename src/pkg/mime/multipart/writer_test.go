@@ -0,0 +1,111 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := NewWriter(&b)
+
+	if err := w.SetBoundary("MyBoundary"); err != nil {
+		t.Fatalf("SetBoundary: %v", err)
+	}
+
+	fw, err := w.CreateFormField("key")
+	if err != nil {
+		t.Fatalf("CreateFormField: %v", err)
+	}
+	fw.Write([]byte("val"))
+
+	fw, err = w.CreateFormFile("file", "myfile.txt")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	fw.Write([]byte("contents of myfile.txt"))
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r := NewReader(&b, w.Boundary())
+
+	part, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("part 1: %v", err)
+	}
+	if g, e := part.FormName(), "key"; g != e {
+		t.Errorf("part 1: FormName() = %q; want %q", g, e)
+	}
+	slurp, err := ioutil.ReadAll(part)
+	if err != nil {
+		t.Fatalf("part 1: ReadAll: %v", err)
+	}
+	if string(slurp) != "val" {
+		t.Errorf("part 1: got %q", slurp)
+	}
+
+	part, err = r.NextPart()
+	if err != nil {
+		t.Fatalf("part 2: %v", err)
+	}
+	if g, e := part.FormName(), "file"; g != e {
+		t.Errorf("part 2: FormName() = %q; want %q", g, e)
+	}
+	if g, e := part.FileName(), "myfile.txt"; g != e {
+		t.Errorf("part 2: FileName() = %q; want %q", g, e)
+	}
+	slurp, err = ioutil.ReadAll(part)
+	if err != nil {
+		t.Fatalf("part 2: ReadAll: %v", err)
+	}
+	if string(slurp) != "contents of myfile.txt" {
+		t.Errorf("part 2: got %q", slurp)
+	}
+
+	if _, err := r.NextPart(); err != os.EOF {
+		t.Fatalf("expected os.EOF after final part; got %v", err)
+	}
+}
+
+func TestWriterSetBoundary(t *testing.T) {
+	tests := []struct {
+		b  string
+		ok bool
+	}{
+		{"abc", true},
+		{"", false},
+		{strings.Repeat("x", 70), true},
+		{strings.Repeat("x", 71), false},
+		{"bad!boundary", false},
+		{"semi;colon", false},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		w := NewWriter(&b)
+		err := w.SetBoundary(tt.b)
+		if got := err == nil; got != tt.ok {
+			t.Errorf("SetBoundary(%q) success=%v; want %v (err=%v)", tt.b, got, tt.ok, err)
+		}
+	}
+}
+
+func TestWriterEscapesFileName(t *testing.T) {
+	var b bytes.Buffer
+	w := NewWriter(&b)
+	w.SetBoundary("BOUNDARY")
+	if _, err := w.CreateFormFile("file", `fun"ny".txt`); err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	if !strings.Contains(b.String(), `filename="fun\"ny\".txt"`) {
+		t.Errorf("escaped filename not found in header: %q", b.String())
+	}
+}